@@ -593,15 +593,19 @@ func (c *Controller) runPreflightCheck(cluster *corev1.StorageCluster) error {
 		Status:  corev1.ClusterConditionStatusInProgress,
 	}
 
-	// Do the preflight check for eks only for now to check the cloud drive permission
-	if preflight.IsEKS() {
+	// Do the preflight check for eks, azure and gke only for now to check the cloud drive permission
+	if preflight.IsEKS() || preflight.IsAzure() || preflight.IsGKE() {
 		//  Only executed once in 1st pre-flight loop cycle.  If preflight condition is not set its 1st time through  pre-flight loop
 		condition = util.GetStorageClusterCondition(cluster, pxutil.PortworxComponentName, corev1.ClusterConditionTypePreflight)
 		if condition == nil {
 			if err = preflight.Instance().CheckCloudDrivePermission(cluster); err != nil {
+				platform := preflight.Instance().K8sDistributionName()
+				if platform == "" {
+					platform = preflight.Instance().ProviderName()
+				}
 				condition = defCondition
 				condition.Status = corev1.ClusterConditionStatusFailed
-				condition.Message = "permission check for eks cloud drive failed"
+				condition.Message = fmt.Sprintf("permission check for %s cloud drive failed", platform)
 				logrus.WithError(err).Errorf(condition.Message)
 			}
 		}
@@ -0,0 +1,300 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/libopenstorage/cloudops"
+	corev1 "github.com/libopenstorage/operator/pkg/apis/core/v1"
+	"github.com/libopenstorage/operator/pkg/util/k8s"
+	coreops "github.com/portworx/sched-ops/k8s/core"
+)
+
+const (
+	azureClientIDEnvName     = "AZURE_CLIENT_ID"
+	azureClientSecretEnvName = "AZURE_CLIENT_SECRET"
+	azureTenantIDEnvName     = "AZURE_TENANT_ID"
+
+	azureDefaultARMEndpoint   = "https://management.azure.com"
+	azureDefaultLoginEndpoint = "https://login.microsoftonline.com"
+	azureDefaultIMDSEndpoint  = "http://169.254.169.254"
+
+	azurePermissionsAPIVersion = "2022-04-01"
+	azureIMDSTokenAPIVersion   = "2018-02-01"
+	azureVMSSResourceType      = "virtualMachineScaleSets"
+)
+
+var (
+	// azureDiskActions are the managed disk operations Portworx needs in the node resource group
+	azureDiskActions = []string{
+		"Microsoft.Compute/disks/read",
+		"Microsoft.Compute/disks/write",
+		"Microsoft.Compute/disks/delete",
+	}
+	// azureVMActions are needed to attach and detach disks on standalone VMs
+	azureVMActions = []string{
+		"Microsoft.Compute/virtualMachines/read",
+		"Microsoft.Compute/virtualMachines/write",
+	}
+	// azureVMSSActions are needed to attach and detach disks on scale set VMs, used by AKS node pools
+	azureVMSSActions = []string{
+		"Microsoft.Compute/virtualMachineScaleSets/virtualMachines/read",
+		"Microsoft.Compute/virtualMachineScaleSets/virtualMachines/write",
+	}
+)
+
+type azure struct {
+	checker
+	httpClient    *http.Client
+	armEndpoint   string
+	loginEndpoint string
+	imdsEndpoint  string
+}
+
+// azureNodeResource is the Azure resource of a k8s node, parsed from its provider ID, e.g.
+// azure:///subscriptions/<id>/resourceGroups/<group>/providers/Microsoft.Compute/virtualMachineScaleSets/<vmss>/virtualMachines/0
+type azureNodeResource struct {
+	subscriptionID string
+	resourceGroup  string
+	isVMSS         bool
+}
+
+type azureCredentials struct {
+	clientID     string
+	clientSecret string
+	tenantID     string
+}
+
+type azureToken struct {
+	AccessToken string `json:"access_token"`
+}
+
+type azurePermission struct {
+	Actions    []string `json:"actions"`
+	NotActions []string `json:"notActions"`
+}
+
+type azurePermissionList struct {
+	Value []azurePermission `json:"value"`
+}
+
+func (a *azure) init() {
+	if a.httpClient == nil {
+		a.httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	if a.armEndpoint == "" {
+		a.armEndpoint = azureDefaultARMEndpoint
+	}
+	if a.loginEndpoint == "" {
+		a.loginEndpoint = azureDefaultLoginEndpoint
+	}
+	if a.imdsEndpoint == "" {
+		a.imdsEndpoint = azureDefaultIMDSEndpoint
+	}
+}
+
+// CheckCloudDrivePermission checks the service principal provided in the StorageCluster, or the managed identity
+// of the node if none is provided, is allowed to create, attach and delete managed disks in the node resource group
+func (a *azure) CheckCloudDrivePermission(cluster *corev1.StorageCluster) error {
+	a.init()
+	logrus.Info("preflight starting azure cloud permission check")
+
+	node, err := getAzureNodeResource()
+	if err != nil {
+		return err
+	}
+
+	creds, err := a.getAzureCredentials(cluster)
+	if err != nil {
+		return err
+	}
+
+	token, err := a.getToken(creds)
+	if err != nil {
+		return fmt.Errorf("preflight failed to get azure access token, check the provided credentials: %v", err)
+	}
+
+	permissions, err := a.listPermissions(node, token)
+	if err != nil {
+		return fmt.Errorf("preflight failed to list permissions on resource group %s: %v", node.resourceGroup, err)
+	}
+
+	requiredActions := append([]string{}, azureDiskActions...)
+	if node.isVMSS {
+		requiredActions = append(requiredActions, azureVMSSActions...)
+	} else {
+		requiredActions = append(requiredActions, azureVMActions...)
+	}
+
+	var missingActions []string
+	for _, action := range requiredActions {
+		if !azureActionAllowed(permissions, action) {
+			missingActions = append(missingActions, action)
+		}
+	}
+	if len(missingActions) > 0 {
+		return fmt.Errorf("preflight found missing permissions on resource group %s: %s",
+			node.resourceGroup, strings.Join(missingActions, ", "))
+	}
+
+	logrus.Infof("preflight check for azure cloud permission passed")
+	return nil
+}
+
+// getAzureCredentials returns the service principal credentials provided in the StorageCluster,
+// usually from the px-azure secret, or nil if none are provided and the managed identity should be used
+func (a *azure) getAzureCredentials(cluster *corev1.StorageCluster) (*azureCredentials, error) {
+	envVars := make(map[string]*v1.EnvVar)
+	for _, env := range cluster.Spec.Env {
+		if env.Name == azureClientIDEnvName || env.Name == azureClientSecretEnvName || env.Name == azureTenantIDEnvName {
+			envVars[env.Name] = env.DeepCopy()
+		}
+	}
+	if len(envVars) == 0 {
+		logrus.Debugf("no azure credentials provided, will use managed identity instead")
+		return nil, nil
+	}
+
+	values := make(map[string]string)
+	for _, name := range []string{azureClientIDEnvName, azureClientSecretEnvName, azureTenantIDEnvName} {
+		if env, ok := envVars[name]; ok {
+			value, err := k8s.GetValueFromEnvVar(context.TODO(), a.k8sClient, env, cluster.Namespace)
+			if err != nil {
+				return nil, err
+			}
+			values[name] = value
+		}
+		if values[name] == "" {
+			return nil, fmt.Errorf("%s, %s and %s need to be provided",
+				azureClientIDEnvName, azureClientSecretEnvName, azureTenantIDEnvName)
+		}
+	}
+
+	return &azureCredentials{
+		clientID:     values[azureClientIDEnvName],
+		clientSecret: values[azureClientSecretEnvName],
+		tenantID:     values[azureTenantIDEnvName],
+	}, nil
+}
+
+// getToken gets an Azure Resource Manager access token for the service principal, or for the managed identity if creds is nil
+func (a *azure) getToken(creds *azureCredentials) (string, error) {
+	var req *http.Request
+	var err error
+	if creds != nil {
+		form := url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {creds.clientID},
+			"client_secret": {creds.clientSecret},
+			"scope":         {azureDefaultARMEndpoint + "/.default"},
+		}
+		req, err = http.NewRequest(http.MethodPost,
+			fmt.Sprintf("%s/%s/oauth2/v2.0/token", a.loginEndpoint, url.PathEscape(creds.tenantID)),
+			strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		query := url.Values{
+			"api-version": {azureIMDSTokenAPIVersion},
+			"resource":    {azureDefaultARMEndpoint + "/"},
+		}
+		req, err = http.NewRequest(http.MethodGet,
+			fmt.Sprintf("%s/metadata/identity/oauth2/token?%s", a.imdsEndpoint, query.Encode()), nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Metadata", "true")
+	}
+
+	token := &azureToken{}
+	if err := doJSONRequest(a.httpClient, req, token); err != nil {
+		return "", err
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("empty access token returned")
+	}
+	return token.AccessToken, nil
+}
+
+func (a *azure) listPermissions(node *azureNodeResource, token string) ([]azurePermission, error) {
+	req, err := http.NewRequest(http.MethodGet,
+		fmt.Sprintf("%s/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Authorization/permissions?api-version=%s",
+			a.armEndpoint, url.PathEscape(node.subscriptionID), url.PathEscape(node.resourceGroup), azurePermissionsAPIVersion),
+		nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	permissionList := &azurePermissionList{}
+	if err := doJSONRequest(a.httpClient, req, permissionList); err != nil {
+		return nil, err
+	}
+	return permissionList.Value, nil
+}
+
+// getAzureNodeResource returns the Azure resource of the first k8s node with an azure provider ID
+func getAzureNodeResource() (*azureNodeResource, error) {
+	nodeList, err := coreops.Instance().GetNodes()
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range nodeList.Items {
+		if !strings.HasPrefix(node.Spec.ProviderID, string(cloudops.Azure)+"://") {
+			continue
+		}
+		if resource := parseAzureProviderID(node.Spec.ProviderID); resource != nil {
+			return resource, nil
+		}
+	}
+	return nil, fmt.Errorf("preflight failed to find the azure resource group from node provider IDs")
+}
+
+func parseAzureProviderID(providerID string) *azureNodeResource {
+	resource := &azureNodeResource{}
+	tokens := strings.Split(strings.TrimPrefix(providerID, string(cloudops.Azure)+"://"), "/")
+	for i := 0; i < len(tokens)-1; i++ {
+		switch strings.ToLower(tokens[i]) {
+		case "subscriptions":
+			resource.subscriptionID = tokens[i+1]
+		case "resourcegroups":
+			resource.resourceGroup = tokens[i+1]
+		case strings.ToLower(azureVMSSResourceType):
+			resource.isVMSS = true
+		}
+	}
+	if resource.subscriptionID == "" || resource.resourceGroup == "" {
+		return nil
+	}
+	return resource
+}
+
+// azureActionAllowed returns whether any permission grants the action without excluding it, action patterns may use wildcards
+func azureActionAllowed(permissions []azurePermission, action string) bool {
+	for _, permission := range permissions {
+		if azureActionMatchesAny(permission.Actions, action) && !azureActionMatchesAny(permission.NotActions, action) {
+			return true
+		}
+	}
+	return false
+}
+
+func azureActionMatchesAny(patterns []string, action string) bool {
+	for _, pattern := range patterns {
+		re := "(?i)^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+		if matched, err := regexp.MatchString(re, action); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
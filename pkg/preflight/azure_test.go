@@ -0,0 +1,169 @@
+package preflight
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakek8sclient "k8s.io/client-go/kubernetes/fake"
+
+	corev1 "github.com/libopenstorage/operator/pkg/apis/core/v1"
+	testutil "github.com/libopenstorage/operator/pkg/util/test"
+	coreops "github.com/portworx/sched-ops/k8s/core"
+)
+
+const (
+	testAzureVMSSProviderID = "azure:///subscriptions/sub-id/resourceGroups/mc_rg/providers/Microsoft.Compute/virtualMachineScaleSets/aks-pool/virtualMachines/0"
+	testAzureVMProviderID   = "azure:///subscriptions/sub-id/resourceGroups/vm_rg/providers/Microsoft.Compute/virtualMachines/node1"
+	testAzureToken          = "test-token"
+)
+
+// fakeAzureServer serves token and permission requests, permissions are returned for any resource group
+func fakeAzureServer(t *testing.T, permissions []azurePermission) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tenant-id/oauth2/v2.0/token":
+			assert.NoError(t, r.ParseForm())
+			if r.PostForm.Get("client_id") != "client-id" || r.PostForm.Get("client_secret") != "client-secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			assert.NoError(t, json.NewEncoder(w).Encode(azureToken{AccessToken: testAzureToken}))
+		case "/metadata/identity/oauth2/token":
+			assert.Equal(t, "true", r.Header.Get("Metadata"))
+			assert.NoError(t, json.NewEncoder(w).Encode(azureToken{AccessToken: testAzureToken}))
+		default:
+			assert.Equal(t, "Bearer "+testAzureToken, r.Header.Get("Authorization"))
+			assert.Equal(t, azurePermissionsAPIVersion, r.URL.Query().Get("api-version"))
+			assert.NoError(t, json.NewEncoder(w).Encode(azurePermissionList{Value: permissions}))
+		}
+	}))
+}
+
+func newTestAzureChecker(server *httptest.Server, nodes ...v1.Node) *azure {
+	coreops.SetInstance(coreops.New(fakek8sclient.NewSimpleClientset(&v1.NodeList{Items: nodes})))
+	return &azure{
+		checker:       checker{k8sClient: testutil.FakeK8sClient()},
+		httpClient:    server.Client(),
+		armEndpoint:   server.URL,
+		loginEndpoint: server.URL,
+		imdsEndpoint:  server.URL,
+	}
+}
+
+func TestAzureCloudPermissionManagedIdentity(t *testing.T) {
+	cluster := &corev1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-cluster",
+			Namespace: "kube-test",
+		},
+	}
+	vmssNode := v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}, Spec: v1.NodeSpec{ProviderID: testAzureVMSSProviderID}}
+
+	// TestCase: all permissions granted through a wildcard
+	server := fakeAzureServer(t, []azurePermission{{Actions: []string{"Microsoft.Compute/*"}}})
+	defer server.Close()
+	require.NoError(t, newTestAzureChecker(server, vmssNode).CheckCloudDrivePermission(cluster))
+
+	// TestCase: scale set VM write is excluded by notActions
+	server = fakeAzureServer(t, []azurePermission{{
+		Actions:    []string{"*"},
+		NotActions: []string{"Microsoft.Compute/virtualMachineScaleSets/*/write"},
+	}})
+	defer server.Close()
+	err := newTestAzureChecker(server, vmssNode).CheckCloudDrivePermission(cluster)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "missing permissions on resource group mc_rg: Microsoft.Compute/virtualMachineScaleSets/virtualMachines/write")
+
+	// TestCase: disk permissions only, standalone VM write is missing
+	vmNode := v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}, Spec: v1.NodeSpec{ProviderID: testAzureVMProviderID}}
+	server = fakeAzureServer(t, []azurePermission{
+		{Actions: []string{"Microsoft.Compute/disks/*"}},
+		{Actions: []string{"Microsoft.Compute/virtualMachines/read", "Microsoft.Compute/virtualMachineScaleSets/virtualMachines/write"}},
+	})
+	defer server.Close()
+	err = newTestAzureChecker(server, vmNode).CheckCloudDrivePermission(cluster)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "missing permissions on resource group vm_rg: Microsoft.Compute/virtualMachines/write")
+
+	// TestCase: no azure node to get the resource group from
+	err = newTestAzureChecker(server, v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}).CheckCloudDrivePermission(cluster)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to find the azure resource group")
+}
+
+func TestAzureCloudPermissionServicePrincipal(t *testing.T) {
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "px-azure",
+			Namespace: "kube-test",
+		},
+		Data: map[string][]byte{
+			azureClientIDEnvName:     []byte("client-id"),
+			azureClientSecretEnvName: []byte("client-secret"),
+			azureTenantIDEnvName:     []byte("tenant-id"),
+		},
+	}
+	envFromSecret := func(name string) v1.EnvVar {
+		return v1.EnvVar{
+			Name: name,
+			ValueFrom: &v1.EnvVarSource{
+				SecretKeyRef: &v1.SecretKeySelector{
+					LocalObjectReference: v1.LocalObjectReference{Name: secret.Name},
+					Key:                  name,
+				},
+			},
+		}
+	}
+	cluster := &corev1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-cluster",
+			Namespace: "kube-test",
+		},
+		Spec: corev1.StorageClusterSpec{
+			CommonConfig: corev1.CommonConfig{
+				Env: []v1.EnvVar{
+					envFromSecret(azureClientIDEnvName),
+					envFromSecret(azureClientSecretEnvName),
+					envFromSecret(azureTenantIDEnvName),
+				},
+			},
+		},
+	}
+	vmssNode := v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}, Spec: v1.NodeSpec{ProviderID: testAzureVMSSProviderID}}
+	server := fakeAzureServer(t, []azurePermission{{Actions: []string{"*"}}})
+	defer server.Close()
+
+	// TestCase: valid credentials from px-azure secret
+	a := newTestAzureChecker(server, vmssNode)
+	a.k8sClient = testutil.FakeK8sClient(secret)
+	require.NoError(t, a.CheckCloudDrivePermission(cluster))
+
+	// TestCase: invalid credentials
+	secret.Data[azureClientSecretEnvName] = []byte("wrong-secret")
+	a.k8sClient = testutil.FakeK8sClient(secret)
+	err := a.CheckCloudDrivePermission(cluster)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to get azure access token")
+
+	// TestCase: tenant ID is not provided
+	cluster.Spec.Env = cluster.Spec.Env[:2]
+	err = a.CheckCloudDrivePermission(cluster)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "AZURE_CLIENT_ID, AZURE_CLIENT_SECRET and AZURE_TENANT_ID need to be provided")
+}
+
+func TestParseAzureProviderID(t *testing.T) {
+	resource := parseAzureProviderID(testAzureVMSSProviderID)
+	require.Equal(t, &azureNodeResource{subscriptionID: "sub-id", resourceGroup: "mc_rg", isVMSS: true}, resource)
+
+	resource = parseAzureProviderID(testAzureVMProviderID)
+	require.Equal(t, &azureNodeResource{subscriptionID: "sub-id", resourceGroup: "vm_rg"}, resource)
+
+	require.Nil(t, parseAzureProviderID("azure://node-id-1"))
+}
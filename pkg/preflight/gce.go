@@ -1,9 +1,211 @@
 package preflight
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/libopenstorage/cloudops"
+	corev1 "github.com/libopenstorage/operator/pkg/apis/core/v1"
+	coreops "github.com/portworx/sched-ops/k8s/core"
+)
+
 const (
 	gkeDistribution = "gke"
+
+	gceDefaultMetadataEndpoint        = "http://metadata.google.internal"
+	gceDefaultResourceManagerEndpoint = "https://cloudresourcemanager.googleapis.com"
+
+	gceCloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+	gceComputeScope       = "https://www.googleapis.com/auth/compute"
+)
+
+var (
+	// gceDiskPermissions are the persistent disk operations Portworx needs in the project
+	gceDiskPermissions = []string{
+		"compute.disks.create",
+		"compute.disks.get",
+		"compute.disks.list",
+		"compute.disks.delete",
+		"compute.disks.resize",
+		"compute.disks.setLabels",
+		"compute.disks.use",
+		"compute.instances.attachDisk",
+		"compute.instances.detachDisk",
+	}
 )
 
 type gce struct {
 	checker
+	httpClient              *http.Client
+	metadataEndpoint        string
+	resourceManagerEndpoint string
+}
+
+type gceToken struct {
+	AccessToken string `json:"access_token"`
+}
+
+type gcePermissions struct {
+	Permissions []string `json:"permissions"`
+}
+
+func (g *gce) init() {
+	if g.httpClient == nil {
+		g.httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	if g.metadataEndpoint == "" {
+		g.metadataEndpoint = gceDefaultMetadataEndpoint
+	}
+	if g.resourceManagerEndpoint == "" {
+		g.resourceManagerEndpoint = gceDefaultResourceManagerEndpoint
+	}
+}
+
+// CheckCloudDrivePermission checks the service account of the metadata server, i.e. the node service account
+// or the Workload Identity binding, has the scopes and IAM permissions to create, attach and delete persistent
+// disks in the project of the cluster nodes
+func (g *gce) CheckCloudDrivePermission(cluster *corev1.StorageCluster) error {
+	g.init()
+	logrus.Info("preflight starting gke cloud permission check")
+
+	project, zones, err := getGCEProjectAndZones()
+	if err != nil {
+		return err
+	}
+
+	scopes, err := g.getMetadata("instance/service-accounts/default/scopes")
+	if err != nil {
+		return fmt.Errorf("preflight failed to get service account scopes: %v", err)
+	}
+	if !gceHasComputeScope(strings.Fields(scopes)) {
+		return fmt.Errorf("preflight found service account is missing the %s or %s scope",
+			gceComputeScope, gceCloudPlatformScope)
+	}
+
+	tokenJSON, err := g.getMetadata("instance/service-accounts/default/token")
+	if err != nil {
+		return fmt.Errorf("preflight failed to get service account access token: %v", err)
+	}
+	token := &gceToken{}
+	if err := json.Unmarshal([]byte(tokenJSON), token); err != nil || token.AccessToken == "" {
+		return fmt.Errorf("preflight failed to parse service account access token: %v", err)
+	}
+
+	granted, err := g.testIAMPermissions(project, token.AccessToken)
+	if err != nil {
+		return fmt.Errorf("preflight failed to test permissions in project %s: %v", project, err)
+	}
+
+	grantedSet := make(map[string]bool)
+	for _, permission := range granted {
+		grantedSet[permission] = true
+	}
+	var missingPermissions []string
+	for _, permission := range gceDiskPermissions {
+		if !grantedSet[permission] {
+			missingPermissions = append(missingPermissions, permission)
+		}
+	}
+	if len(missingPermissions) > 0 {
+		return fmt.Errorf("preflight found missing permissions in project %s: %s",
+			project, strings.Join(missingPermissions, ", "))
+	}
+
+	logrus.Infof("preflight check for gke cloud permission passed in project %s, zones %v", project, zones)
+	return nil
+}
+
+// getMetadata returns the value of the given path from the GCE metadata server
+func (g *gce) getMetadata(metadataPath string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/computeMetadata/v1/%s", g.metadataEndpoint, metadataPath), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata %s returned status code %d: %s", metadataPath, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return string(body), nil
+}
+
+// testIAMPermissions returns the subset of gceDiskPermissions granted to the caller in the project
+func (g *gce) testIAMPermissions(project, token string) ([]string, error) {
+	body, err := json.Marshal(&gcePermissions{Permissions: gceDiskPermissions})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost,
+		fmt.Sprintf("%s/v1/projects/%s:testIamPermissions", g.resourceManagerEndpoint, project),
+		bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	granted := &gcePermissions{}
+	if err := doJSONRequest(g.httpClient, req, granted); err != nil {
+		return nil, err
+	}
+	return granted.Permissions, nil
+}
+
+// getGCEProjectAndZones returns the project and the sorted zones of the k8s nodes,
+// parsed from provider IDs like gce://<project>/<zone>/<instance>
+func getGCEProjectAndZones() (string, []string, error) {
+	nodeList, err := coreops.Instance().GetNodes()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var project string
+	zoneSet := make(map[string]bool)
+	for _, node := range nodeList.Items {
+		tokens := strings.Split(strings.TrimPrefix(node.Spec.ProviderID, string(cloudops.GCE)+"://"), "/")
+		if !strings.HasPrefix(node.Spec.ProviderID, string(cloudops.GCE)+"://") || len(tokens) != 3 {
+			continue
+		}
+		if project == "" {
+			project = tokens[0]
+		}
+		zoneSet[tokens[1]] = true
+	}
+	if project == "" {
+		return "", nil, fmt.Errorf("preflight failed to find the gce project from node provider IDs")
+	}
+
+	var zones []string
+	for zone := range zoneSet {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+	return project, zones, nil
+}
+
+func gceHasComputeScope(scopes []string) bool {
+	for _, scope := range scopes {
+		if scope == gceCloudPlatformScope || scope == gceComputeScope {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,102 @@
+package preflight
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakek8sclient "k8s.io/client-go/kubernetes/fake"
+
+	corev1 "github.com/libopenstorage/operator/pkg/apis/core/v1"
+	coreops "github.com/portworx/sched-ops/k8s/core"
+)
+
+const testGCEToken = "test-token"
+
+// fakeGCEServer serves metadata and testIamPermissions requests, granting the given permissions
+func fakeGCEServer(t *testing.T, scopes string, granted []string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/computeMetadata/v1/instance/service-accounts/default/scopes":
+			assert.Equal(t, "Google", r.Header.Get("Metadata-Flavor"))
+			_, err := w.Write([]byte(scopes))
+			assert.NoError(t, err)
+		case "/computeMetadata/v1/instance/service-accounts/default/token":
+			assert.Equal(t, "Google", r.Header.Get("Metadata-Flavor"))
+			assert.NoError(t, json.NewEncoder(w).Encode(gceToken{AccessToken: testGCEToken}))
+		case "/v1/projects/test-project:testIamPermissions":
+			assert.Equal(t, "Bearer "+testGCEToken, r.Header.Get("Authorization"))
+			requested := &gcePermissions{}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(requested))
+			assert.ElementsMatch(t, gceDiskPermissions, requested.Permissions)
+			assert.NoError(t, json.NewEncoder(w).Encode(gcePermissions{Permissions: granted}))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func newTestGCEChecker(server *httptest.Server, nodes ...v1.Node) *gce {
+	coreops.SetInstance(coreops.New(fakek8sclient.NewSimpleClientset(&v1.NodeList{Items: nodes})))
+	return &gce{
+		httpClient:              server.Client(),
+		metadataEndpoint:        server.URL,
+		resourceManagerEndpoint: server.URL,
+	}
+}
+
+func TestGKECloudPermission(t *testing.T) {
+	cluster := &corev1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-cluster",
+			Namespace: "kube-test",
+		},
+	}
+	nodes := []v1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node1"}, Spec: v1.NodeSpec{ProviderID: "gce://test-project/us-east1-b/node1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node2"}, Spec: v1.NodeSpec{ProviderID: "gce://test-project/us-east1-c/node2"}},
+	}
+
+	// TestCase: all permissions granted
+	server := fakeGCEServer(t, gceCloudPlatformScope+"\n", gceDiskPermissions)
+	defer server.Close()
+	require.NoError(t, newTestGCEChecker(server, nodes...).CheckCloudDrivePermission(cluster))
+
+	// TestCase: node service account without compute scope
+	server = fakeGCEServer(t, "https://www.googleapis.com/auth/devstorage.read_only\nhttps://www.googleapis.com/auth/logging.write\n", gceDiskPermissions)
+	defer server.Close()
+	err := newTestGCEChecker(server, nodes...).CheckCloudDrivePermission(cluster)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "service account is missing the")
+
+	// TestCase: attach and detach permissions are not granted
+	server = fakeGCEServer(t, gceComputeScope+"\n", gceDiskPermissions[:len(gceDiskPermissions)-2])
+	defer server.Close()
+	err = newTestGCEChecker(server, nodes...).CheckCloudDrivePermission(cluster)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "missing permissions in project test-project: compute.instances.attachDisk, compute.instances.detachDisk")
+
+	// TestCase: no gce node to get the project from
+	err = newTestGCEChecker(server, v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}).CheckCloudDrivePermission(cluster)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to find the gce project")
+}
+
+func TestGetGCEProjectAndZones(t *testing.T) {
+	coreops.SetInstance(coreops.New(fakek8sclient.NewSimpleClientset(&v1.NodeList{Items: []v1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node1"}, Spec: v1.NodeSpec{ProviderID: "gce://test-project/us-east1-c/node1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node2"}, Spec: v1.NodeSpec{ProviderID: "gce://test-project/us-east1-b/node2"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node3"}, Spec: v1.NodeSpec{ProviderID: "gce://test-project/us-east1-b/node3"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node4"}, Spec: v1.NodeSpec{ProviderID: "gce://node-id-4"}},
+	}})))
+
+	project, zones, err := getGCEProjectAndZones()
+	require.NoError(t, err)
+	require.Equal(t, "test-project", project)
+	require.Equal(t, []string{"us-east1-b", "us-east1-c"}, zones)
+}
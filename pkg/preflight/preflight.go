@@ -89,6 +89,10 @@ func InitPreflightChecker(client client.Client) error {
 		instance = &aws{
 			checker: *c,
 		}
+	} else if IsAzure() {
+		instance = &azure{
+			checker: *c,
+		}
 	} else if IsGKE() {
 		instance = &gce{
 			checker: *c,
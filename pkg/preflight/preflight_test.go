@@ -52,7 +52,9 @@ func TestDefaultProviders(t *testing.T) {
 	c = Instance()
 	require.Equal(t, cloudops.Azure, c.ProviderName())
 	require.Empty(t, c.K8sDistributionName())
-	require.NoError(t, c.CheckCloudDrivePermission(cluster))
+	err = c.CheckCloudDrivePermission(cluster)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to find the azure resource group")
 
 	// TestCase: init gce cloud provider
 	fakeK8sNodes = &v1.NodeList{Items: []v1.Node{
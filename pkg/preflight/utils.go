@@ -1,6 +1,12 @@
 package preflight
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
 	"github.com/libopenstorage/cloudops"
 )
 
@@ -41,3 +47,22 @@ func RunningOnCloud() bool {
 	// TODO: add other clouds
 	return IsEKS()
 }
+
+// doJSONRequest sends the request and decodes the JSON response body into out,
+// responses other than 200 OK are returned as errors including the body
+func doJSONRequest(httpClient *http.Client, req *http.Request, out interface{}) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s returned status code %d: %s", req.Method, req.URL.Path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, out)
+}
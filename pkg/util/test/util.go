@@ -5212,8 +5212,6 @@ func GetK8SVersion() (string, error) {
 
 // GetImagesFromVersionURL gets images from version URL
 func GetImagesFromVersionURL(url, k8sVersion string) (map[string]string, error) {
-	imageListMap := make(map[string]string)
-
 	// Construct PX version URL
 	pxVersionURL, err := ConstructVersionURL(url, k8sVersion)
 	if err != nil {
@@ -5231,12 +5229,21 @@ func GetImagesFromVersionURL(url, k8sVersion string) (map[string]string, error)
 		return nil, fmt.Errorf("failed to read response: %+v", resp.Body)
 	}
 
-	for _, line := range strings.Split(string(htmlData), "\n") {
+	return ParseImagesFromVersionManifest(htmlData), nil
+}
+
+// ParseImagesFromVersionManifest parses component images from the content returned by the version URL
+func ParseImagesFromVersionManifest(data []byte) map[string]string {
+	imageListMap := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
 		if strings.Contains(line, "components") || line == "" {
 			continue
 		}
 
 		imageNameSplit := strings.Split(strings.TrimSpace(line), ": ")
+		if len(imageNameSplit) < 2 {
+			continue
+		}
 
 		if strings.Contains(line, "version") {
 			imageListMap["version"] = fmt.Sprintf("portworx/oci-monitor:%s", imageNameSplit[1])
@@ -5244,8 +5251,7 @@ func GetImagesFromVersionURL(url, k8sVersion string) (map[string]string, error)
 		}
 		imageListMap[imageNameSplit[0]] = imageNameSplit[1]
 	}
-
-	return imageListMap, nil
+	return imageListMap
 }
 
 // ConstructVersionURL constructs Portworx version URL that contains component images
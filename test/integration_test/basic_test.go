@@ -11,14 +11,12 @@ import (
 
 	"github.com/hashicorp/go-version"
 
-	"github.com/libopenstorage/operator/drivers/storage/portworx"
 	corev1 "github.com/libopenstorage/operator/pkg/apis/core/v1"
 	k8sutil "github.com/libopenstorage/operator/pkg/util/k8s"
 	testutil "github.com/libopenstorage/operator/pkg/util/test"
 	"github.com/libopenstorage/operator/test/integration_test/types"
 	ci_utils "github.com/libopenstorage/operator/test/integration_test/utils"
 	coreops "github.com/portworx/sched-ops/k8s/core"
-	"github.com/portworx/sched-ops/k8s/operator"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 	appsv1 "k8s.io/api/apps/v1"
@@ -419,39 +417,12 @@ func BasicUpgradeStorageCluster(tc *types.TestCase) func(*testing.T) {
 			}
 		}
 
-		var lastHopURL string
-		for i, hopURL := range ci_utils.PxUpgradeHopsURLList {
-			// Get versions from URL
-			specImages, err := testutil.GetImagesFromVersionURL(hopURL, ci_utils.K8sVersion)
-			require.NoError(t, err)
-			if i == 0 {
-				// Deploy cluster
-				logrus.Infof("Deploying starting cluster using %s", hopURL)
-				err := ci_utils.ConstructStorageCluster(cluster, hopURL, specImages)
-				require.NoError(t, err)
-				cluster = ci_utils.DeployAndValidateStorageCluster(cluster, specImages, t)
-			} else {
-				logrus.Infof("Upgrading from %s to %s", lastHopURL, hopURL)
-				// Get live StorageCluster
-				cluster, err := operator.Instance().GetStorageCluster(cluster.Name, cluster.Namespace)
-				require.NoError(t, err)
-
-				err = ci_utils.ConstructStorageCluster(cluster, hopURL, specImages)
-				require.NoError(t, err)
-
-				// Set defaults
-				k8sVersion, _ := version.NewVersion(ci_utils.K8sVersion)
-				portworx.SetPortworxDefaults(cluster, k8sVersion)
-
-				// Update live StorageCluster
-				cluster, err = ci_utils.UpdateStorageCluster(cluster)
-				require.NoError(t, err)
-				logrus.Infof("Validate upgraded StorageCluster %s", cluster.Name)
-				err = testutil.ValidateStorageCluster(specImages, cluster, ci_utils.DefaultValidateUpgradeTimeout, ci_utils.DefaultValidateUpgradeRetryInterval, true, "")
-				require.NoError(t, err)
-			}
-			lastHopURL = hopURL
+		plan := ci_utils.NewUpgradePlan(ci_utils.PxUpgradeHopsURLList)
+		cluster, reports, err := plan.Run(cluster)
+		for _, report := range reports {
+			logrus.Infof("Upgrade hop to %s took %s, Err: %v", report.ToURL, report.Duration, report.Err)
 		}
+		require.NoError(t, err)
 
 		// Delete and validate the deletion
 		ci_utils.UninstallAndValidateStorageCluster(cluster, t)
@@ -6,19 +6,15 @@ package integrationtest
 import (
 	"fmt"
 	"testing"
-	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 	k8sv1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	corev1 "github.com/libopenstorage/operator/pkg/apis/core/v1"
 	testutil "github.com/libopenstorage/operator/pkg/util/test"
 	"github.com/libopenstorage/operator/test/integration_test/types"
 	ci_utils "github.com/libopenstorage/operator/test/integration_test/utils"
-	coreops "github.com/portworx/sched-ops/k8s/core"
 )
 
 // node* is to be used in the Node section of the StorageCluster spec. node0 will select the
@@ -241,13 +237,15 @@ func BasicInstallWithFAFB(tc *types.TestCase) func(*testing.T) {
 		// Check that we have enough backends to support this test: skip early
 		require.NotEmpty(t, tc.PureBackendRequirements)
 		fleetBackends := ci_utils.GenerateFleet(t, ci_utils.PxNamespace, tc.PureBackendRequirements)
+		err := ci_utils.ValidatePureBackendCredentials(fleetBackends)
+		require.NoError(t, err)
 
 		// Construct Portworx StorageCluster object
 		testSpec := tc.TestSpec(t)
 		cluster, ok := testSpec.(*corev1.StorageCluster)
 		require.True(t, ok)
 
-		err := ci_utils.PopulateStorageCluster(tc, cluster)
+		err = ci_utils.PopulateStorageCluster(tc, cluster)
 		require.NoError(t, err)
 
 		// Add extra env variables
@@ -267,7 +265,7 @@ func BasicInstallWithFAFB(tc *types.TestCase) func(*testing.T) {
 
 		// Create px-pure-secret
 		logrus.Infof("Create or update %s in %s", ci_utils.OutputSecretName, ci_utils.PxNamespace)
-		err = createPureSecret(fleetBackends, ci_utils.PxNamespace)
+		err = ci_utils.CreatePureSecret(fleetBackends, ci_utils.PxNamespace)
 		require.NoError(t, err)
 
 		// Install, validation and deletion
@@ -275,45 +273,13 @@ func BasicInstallWithFAFB(tc *types.TestCase) func(*testing.T) {
 
 		// Delete px-pure-secret
 		logrus.Infof("Delete Secret %s", ci_utils.OutputSecretName)
-		err = deletePureSecretIfExists(cluster.Namespace)
+		err = ci_utils.DeletePureSecretIfExists(cluster.Namespace)
 		require.NoError(t, err)
 
 		// TODO: mark test completion using testrail IDs
 	}
 }
 
-func createPureSecret(config types.DiscoveryConfig, namespace string) error {
-	pureJSON, err := config.DumpJSON()
-	if err != nil {
-		return err
-	}
-
-	if err = deletePureSecretIfExists(namespace); err != nil {
-		return err
-	}
-
-	// Wait a little bit
-	time.Sleep(time.Second * 5)
-
-	_, err = coreops.Instance().CreateSecret(&k8sv1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      ci_utils.OutputSecretName,
-			Namespace: namespace,
-		},
-		Data: map[string][]byte{
-			"pure.json": pureJSON,
-		},
-	})
-	return err
-}
-
-func deletePureSecretIfExists(namespace string) error {
-	if err := coreops.Instance().DeleteSecret(ci_utils.OutputSecretName, namespace); !errors.IsNotFound(err) && !errors.IsAlreadyExists(err) {
-		return err
-	}
-	return nil
-}
-
 // ShouldSkipFAFBTest If not enough devices exist to meet the requirements or the source secret does not
 // exist, the test will be skipped.
 func ShouldSkipFAFBTest(tc *types.TestCase) bool {
@@ -323,13 +289,14 @@ func ShouldSkipFAFBTest(tc *types.TestCase) bool {
 	}
 
 	req := tc.PureBackendRequirements
+	sourceBackends := ci_utils.SelectSourceBackends(req)
 	// Check that we have enough devices to meet the requirements
-	if req.RequiredArrays > 0 && len(ci_utils.FAFBSourceConfig.Arrays) < req.RequiredArrays {
-		fmt.Printf("Test requires %d FlashArrays but only %d provided, skipping\n", req.RequiredArrays, len(ci_utils.FAFBSourceConfig.Arrays))
+	if req.RequiredArrays > 0 && len(sourceBackends.Arrays) < req.RequiredArrays {
+		fmt.Printf("Test requires %d FlashArrays but only %d provided, skipping\n", req.RequiredArrays, len(sourceBackends.Arrays))
 		return true
 	}
-	if req.RequiredBlades > 0 && len(ci_utils.FAFBSourceConfig.Blades) < req.RequiredBlades {
-		fmt.Printf("Test requires %d FlashBlades but only %d provided, skipping\n", req.RequiredBlades, len(ci_utils.FAFBSourceConfig.Blades))
+	if req.RequiredBlades > 0 && len(sourceBackends.Blades) < req.RequiredBlades {
+		fmt.Printf("Test requires %d FlashBlades but only %d provided, skipping\n", req.RequiredBlades, len(sourceBackends.Blades))
 		return true
 	}
 	return false
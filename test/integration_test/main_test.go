@@ -34,6 +34,7 @@ func setup() error {
 	var pxUpgradeHopsURLs string
 	var operatorUpgradeHopsImages string
 	var logLevel string
+	var specGenCacheDir string
 	var err error
 
 	flag.StringVar(&ci_utils.PxDockerUsername,
@@ -60,6 +61,10 @@ func setup() error {
 		"portworx-image-override",
 		"",
 		"Portworx Image override, defines what Portworx version will be deployed")
+	flag.StringVar(&specGenCacheDir,
+		"portworx-spec-gen-cache-dir",
+		"",
+		"Directory used to cache stable Portworx Spec Generator responses, caching is disabled if empty")
 	flag.StringVar(&pxUpgradeHopsURLs,
 		"px-upgrade-hops-url-list",
 		"",
@@ -120,6 +125,9 @@ func setup() error {
 		"px-namespace",
 		"kube-system",
 		"Namespace where the operator will be deployed")
+	if err = ci_utils.RegisterTimeoutFlags(flag.CommandLine); err != nil {
+		return err
+	}
 	flag.Parse()
 
 	// Set log level
@@ -135,7 +143,8 @@ func setup() error {
 		return err
 	}
 
-	ci_utils.PxSpecImages, err = test_util.GetImagesFromVersionURL(ci_utils.PxSpecGenURL, ci_utils.K8sVersion)
+	ci_utils.PxSpecGenClient = ci_utils.NewSpecGenClient(specGenCacheDir)
+	ci_utils.PxSpecImages, err = ci_utils.PxSpecGenClient.GetImages(ci_utils.PxSpecGenURL, ci_utils.K8sVersion)
 	if err != nil {
 		return err
 	}
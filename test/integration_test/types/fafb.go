@@ -36,6 +36,9 @@ type PureBackendRequirements struct {
 	// If set to AllAvailableBackends, all backends will have their
 	// credentials set invalid.
 	InvalidArrays, InvalidBlades int
+	// Zone restricts the backends to the ones labeled with the given zone,
+	// if empty backends from all zones can be used.
+	Zone string
 }
 
 // DumpJSON returns this DiscoveryConfig in a JSON byte array,
@@ -24,6 +24,8 @@ var (
 	PxImageOverride string
 	// PxSpecImages contains images parsed from spec gen url
 	PxSpecImages map[string]string
+	// PxSpecGenClient is used to get component images from spec gen urls
+	PxSpecGenClient *SpecGenClient
 
 	// PxUpgradeHopsURLList URL list for upgrade PX test
 	PxUpgradeHopsURLList []string
@@ -74,7 +76,9 @@ var (
 	PxNamespace string
 )
 
-const (
+// Validation timeouts and retry intervals used across the tests. The values below are defaults,
+// which can be overridden with test flags or environment variables, see RegisterTimeoutFlags.
+var (
 	// DefaultValidateDeployTimeout is a default timeout for deployment validation
 	DefaultValidateDeployTimeout = 15 * time.Minute
 	// DefaultValidateDeployRetryInterval is a default retry interval for deployment validation
@@ -100,7 +104,9 @@ const (
 	DefaultValidateApplicationTimeout = 5 * time.Minute
 	// DefaultValidateApplicationRetryInterval is a default retry interval for component validation
 	DefaultValidateApplicationRetryInterval = 5 * time.Second
+)
 
+const (
 	// LabelValueTrue value "true" for a label
 	LabelValueTrue = "true"
 	// LabelValueFalse value "false" for a label
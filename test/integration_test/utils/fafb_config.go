@@ -1,17 +1,21 @@
 package utils
 
 import (
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
-	"sort"
+	"net/http"
 	"strconv"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	corev1 "github.com/libopenstorage/operator/pkg/apis/core/v1"
 	testutil "github.com/libopenstorage/operator/pkg/util/test"
@@ -28,9 +32,18 @@ var (
 	sourceConfigLoadOnce  sync.Once
 )
 
-// AllAvailableBackends can be used in a PureBackendRequirements struct
-// to indicate that all available FlashArrays or FlashBlades should be used
-const AllAvailableBackends = -1
+const (
+	// AllAvailableBackends can be used in a PureBackendRequirements struct
+	// to indicate that all available FlashArrays or FlashBlades should be used
+	AllAvailableBackends = -1
+
+	// PureZoneLabelKey is the label used in pure.json to assign a backend to a zone
+	PureZoneLabelKey = "topology.portworx.io/zone"
+
+	invalidAPIToken = "invalid"
+
+	pureLoginTimeout = 30 * time.Second
+)
 
 func loadSourceConfig(namespace string) func() {
 	return func() {
@@ -90,9 +103,10 @@ func GenerateFleet(t *testing.T, namespace string,
 
 	// We have enough devices for this test, let's make a fleet and give it back for testing
 	newFleet := types.DiscoveryConfig{}
+	sourceFleet := SelectSourceBackends(req)
 
 	addedArrays := 0
-	for _, value := range FAFBSourceConfig.Arrays {
+	for _, value := range sourceFleet.Arrays {
 		// If we have enough arrays, stop now
 		if req.RequiredArrays != AllAvailableBackends && addedArrays >= req.RequiredArrays {
 			break
@@ -106,14 +120,14 @@ func GenerateFleet(t *testing.T, namespace string,
 		}
 		// Invalid backends are in effectively random order because golang map order is not guaranteed
 		if req.InvalidArrays == AllAvailableBackends || addedArrays < req.InvalidArrays {
-			entry.APIToken = "invalid"
+			entry.APIToken = invalidAPIToken
 		}
 		newFleet.Arrays = append(newFleet.Arrays, entry)
 		addedArrays++
 	}
 
 	addedBlades := 0
-	for _, value := range FAFBSourceConfig.Blades {
+	for _, value := range sourceFleet.Blades {
 		// If we have enough blades, stop now
 		if req.RequiredBlades != AllAvailableBackends && addedBlades >= req.RequiredBlades {
 			break
@@ -128,7 +142,7 @@ func GenerateFleet(t *testing.T, namespace string,
 		}
 		// Invalid backends are in effectively random order because golang map order is not guaranteed
 		if req.InvalidBlades == AllAvailableBackends || addedBlades < req.InvalidBlades {
-			entry.APIToken = "invalid"
+			entry.APIToken = invalidAPIToken
 		}
 		newFleet.Blades = append(newFleet.Blades, entry)
 		addedBlades++
@@ -137,18 +151,123 @@ func GenerateFleet(t *testing.T, namespace string,
 	return newFleet
 }
 
+// SelectSourceBackends returns the backends from the source config that can be used for the given requirements
+func SelectSourceBackends(req *types.PureBackendRequirements) types.DiscoveryConfig {
+	if req.Zone == "" {
+		return FAFBSourceConfig
+	}
+
+	selected := types.DiscoveryConfig{}
+	for _, value := range FAFBSourceConfig.Arrays {
+		if value.Labels[PureZoneLabelKey] == req.Zone {
+			selected.Arrays = append(selected.Arrays, value)
+		}
+	}
+	for _, value := range FAFBSourceConfig.Blades {
+		if value.Labels[PureZoneLabelKey] == req.Zone {
+			selected.Blades = append(selected.Blades, value)
+		}
+	}
+	return selected
+}
+
+// ValidatePureBackendCredentials logs in to every FlashArray and FlashBlade in the given config,
+// skipping the backends that were made invalid on purpose, and returns an error listing the ones that failed
+func ValidatePureBackendCredentials(config types.DiscoveryConfig) error {
+	client := &http.Client{
+		Timeout: pureLoginTimeout,
+		Transport: &http.Transport{
+			// Backends in test labs use self-signed certificates
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+
+	var failures []string
+	for _, value := range config.Arrays {
+		if value.APIToken == invalidAPIToken {
+			continue
+		}
+		if err := pureLogin(client, fmt.Sprintf("https://%s/api/2.0/login", value.MgmtEndPoint), value.APIToken); err != nil {
+			failures = append(failures, fmt.Sprintf("FlashArray %s: %v", value.MgmtEndPoint, err))
+		}
+	}
+	for _, value := range config.Blades {
+		if value.APIToken == invalidAPIToken {
+			continue
+		}
+		if err := pureLogin(client, fmt.Sprintf("https://%s/api/login", value.MgmtEndPoint), value.APIToken); err != nil {
+			failures = append(failures, fmt.Sprintf("FlashBlade %s: %v", value.MgmtEndPoint, err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to authenticate to pure backends: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+func pureLogin(client *http.Client, loginURL, apiToken string) error {
+	req, err := http.NewRequest(http.MethodPost, loginURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("api-token", apiToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("login returned status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// CreatePureSecret writes the given config to the OutputSecretName secret, replacing it if it already exists
+func CreatePureSecret(config types.DiscoveryConfig, namespace string) error {
+	pureJSON, err := config.DumpJSON()
+	if err != nil {
+		return err
+	}
+
+	if err = DeletePureSecretIfExists(namespace); err != nil {
+		return err
+	}
+
+	// Wait a little bit
+	time.Sleep(time.Second * 5)
+
+	_, err = coreops.Instance().CreateSecret(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      OutputSecretName,
+			Namespace: namespace,
+		},
+		Data: map[string][]byte{
+			"pure.json": pureJSON,
+		},
+	})
+	return err
+}
+
+// DeletePureSecretIfExists deletes the OutputSecretName secret, if present
+func DeletePureSecretIfExists(namespace string) error {
+	if err := coreops.Instance().DeleteSecret(OutputSecretName, namespace); !errors.IsNotFound(err) && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
 // PopulateStorageCluster populate the cluster name and update node names
 func PopulateStorageCluster(tc *types.TestCase, cluster *corev1.StorageCluster) error {
 	cluster.Name = MakeDNS1123Compatible(strings.Join(tc.TestrailCaseIDs, "-"))
 
-	nodes, err := testutil.GetExpectedPxNodeList(cluster)
+	// Use the same node order as the "node" function of spec templates
+	data, err := GetSpecTemplateData(cluster.Spec.Placement, testutil.IsPxDeployedOnMaster(cluster))
 	if err != nil {
 		return err
 	}
-	names := testutil.ConvertNodeListToNodeNameList(nodes)
-
-	// Sort for consistent order between multiple tests
-	sort.Strings(names)
 
 	// For each node, if the selector looks like "replaceWithNodeNumberN", replace it with
 	// the name of the Nth eligible Portworx node
@@ -163,11 +282,11 @@ func PopulateStorageCluster(tc *types.TestCase, cluster *corev1.StorageCluster)
 			return err
 		}
 
-		if parsedNum >= len(names) {
-			return fmt.Errorf("requested node index %d is larger than eligible worker node count %d", parsedNum, len(names))
+		if parsedNum >= len(data.Nodes) {
+			return fmt.Errorf("requested node index %d is larger than eligible worker node count %d", parsedNum, len(data.Nodes))
 		}
 
-		cluster.Spec.Nodes[i].Selector.NodeName = names[parsedNum]
+		cluster.Spec.Nodes[i].Selector.NodeName = data.Nodes[parsedNum].Name
 	}
 
 	return nil
@@ -45,14 +45,18 @@ func ParseSpecs(filename string) ([]runtime.Object, error) {
 
 // ParseSpecsWithFullPath parses the file and returns all the valid k8s objects
 func ParseSpecsWithFullPath(filename string) ([]runtime.Object, error) {
-	var specs []runtime.Object
 	file, err := os.Open(filename)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
 
-	reader := bufio.NewReader(file)
+	return parseSpecsFromReader(file, filename)
+}
+
+func parseSpecsFromReader(r io.Reader, filename string) ([]runtime.Object, error) {
+	var specs []runtime.Object
+	reader := bufio.NewReader(r)
 	specReader := yaml.NewYAMLReader(reader)
 
 	for {
@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/portworx/sched-ops/task"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/rand"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/libopenstorage/operator/pkg/apis"
+	corev1 "github.com/libopenstorage/operator/pkg/apis/core/v1"
+	k8sutil "github.com/libopenstorage/operator/pkg/util/k8s"
+)
+
+const (
+	// LabelTestNamespace is added to namespaces created by NewTestNamespace, so leftovers are easy to find
+	LabelTestNamespace = "operator-test/namespace"
+
+	uniqueNameSuffixLength = 5
+)
+
+// TestNamespace is a namespace owned by a single test case, so independent test cases can run in parallel
+type TestNamespace struct {
+	// Name is the unique name of the namespace
+	Name string
+	// K8sClient is a k8s client dedicated to the test case
+	K8sClient client.Client
+}
+
+// UniqueName returns a DNS-1123 compatible name made of the given prefix and a random suffix
+func UniqueName(prefix string) string {
+	suffix := rand.String(uniqueNameSuffixLength)
+	maxPrefixLength := 63 - len(suffix) - 1
+	prefix = strings.Trim(MakeDNS1123Compatible(prefix), "-")
+	if len(prefix) > maxPrefixLength {
+		prefix = strings.TrimRight(prefix[:maxPrefixLength], "-")
+	}
+	if prefix == "" {
+		prefix = "test"
+	}
+	return fmt.Sprintf("%s-%s", prefix, suffix)
+}
+
+// NewTestNamespace creates a uniquely named namespace for the test case along with a dedicated k8s client,
+// which is used to create and tear down the namespace. The namespace, and any StorageCluster left in it,
+// is removed when the test finishes, even if it failed.
+func NewTestNamespace(t *testing.T, prefix string) *TestNamespace {
+	s := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(s))
+	require.NoError(t, apis.AddToScheme(s))
+	k8sClient, err := k8sutil.NewK8sClient(s)
+	require.NoError(t, err)
+
+	ns := &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   UniqueName(prefix),
+			Labels: map[string]string{LabelTestNamespace: LabelValueTrue},
+		},
+	}
+	require.NoError(t, k8sClient.Create(context.TODO(), ns))
+	logrus.Infof("Created test namespace [%s] for %s", ns.Name, t.Name())
+
+	testNamespace := &TestNamespace{
+		Name:      ns.Name,
+		K8sClient: k8sClient,
+	}
+	t.Cleanup(func() {
+		if err := testNamespace.teardown(); err != nil {
+			t.Errorf("failed to tear down test namespace [%s], Err: %v", testNamespace.Name, err)
+		}
+	})
+	return testNamespace
+}
+
+// NewParallelTestNamespace marks the test to run in parallel with other parallel tests and returns its own namespace.
+// Only use it for test cases that do not depend on cluster-wide state, e.g. a Portworx cluster running on all nodes.
+func NewParallelTestNamespace(t *testing.T, prefix string) *TestNamespace {
+	t.Parallel()
+	return NewTestNamespace(t, prefix)
+}
+
+// teardown uninstalls StorageClusters left in the namespace before deleting it,
+// since their finalizers would otherwise block the namespace deletion. The namespace
+// is deleted even if uninstalling a StorageCluster failed, and all errors are returned.
+func (tn *TestNamespace) teardown() error {
+	var errs []error
+	clusterList := &corev1.StorageClusterList{}
+	err := tn.K8sClient.List(context.TODO(), clusterList, client.InNamespace(tn.Name))
+	// The StorageCluster CRD is not registered if the test uninstalled PX Operator
+	if err != nil && !errors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+		errs = append(errs, err)
+	}
+	for i := range clusterList.Items {
+		cluster := &clusterList.Items[i]
+		logrus.Infof("Delete leftover StorageCluster [%s] in test namespace [%s]", cluster.Name, tn.Name)
+		if err := tn.uninstallStorageCluster(cluster); err != nil {
+			errs = append(errs, fmt.Errorf("failed to uninstall StorageCluster [%s], Err: %v", cluster.Name, err))
+		}
+	}
+
+	logrus.Infof("Delete test namespace [%s]", tn.Name)
+	if err := tn.deleteAndWait(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: tn.Name}}); err != nil {
+		errs = append(errs, err)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// uninstallStorageCluster wipes Portworx off the nodes, like testutil.UninstallStorageCluster,
+// unless the StorageCluster already has an uninstall delete strategy
+func (tn *TestNamespace) uninstallStorageCluster(cluster *corev1.StorageCluster) error {
+	// The operator keeps updating the StorageCluster status, so retry on conflicts with the latest version
+	var err error
+	for i := 0; i < 5; i++ {
+		if err = tn.K8sClient.Get(context.TODO(), client.ObjectKeyFromObject(cluster), cluster); err != nil {
+			if errors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		if cluster.Spec.DeleteStrategy != nil &&
+			(cluster.Spec.DeleteStrategy.Type == corev1.UninstallAndWipeStorageClusterStrategyType ||
+				cluster.Spec.DeleteStrategy.Type == corev1.UninstallStorageClusterStrategyType) {
+			break
+		}
+
+		cluster.Spec.DeleteStrategy = &corev1.StorageClusterDeleteStrategy{
+			Type: corev1.UninstallAndWipeStorageClusterStrategyType,
+		}
+		err = tn.K8sClient.Update(context.TODO(), cluster)
+		if err != nil && strings.Contains(err.Error(), k8sutil.UpdateRevisionConflictErr) {
+			logrus.Warnf("Failed to update StorageCluster [%s], Err: %s, Retrying..", cluster.Name, err)
+			continue
+		}
+		break
+	}
+	if err != nil {
+		return err
+	}
+	return tn.deleteAndWait(cluster)
+}
+
+// deleteAndWait deletes the given object and waits until it is gone
+func (tn *TestNamespace) deleteAndWait(obj client.Object) error {
+	if err := tn.K8sClient.Delete(context.TODO(), obj); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	key := client.ObjectKeyFromObject(obj)
+	t := func() (interface{}, bool, error) {
+		err := tn.K8sClient.Get(context.TODO(), key, obj)
+		if errors.IsNotFound(err) {
+			return nil, false, nil
+		} else if err != nil {
+			return nil, true, err
+		}
+		return nil, true, fmt.Errorf("%T [%s] is still present", obj, key.Name)
+	}
+	_, err := task.DoRetryWithTimeout(t, DefaultValidateUninstallTimeout, DefaultValidateUninstallRetryInterval)
+	return err
+}
@@ -9,6 +9,9 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 
 	appops "github.com/portworx/sched-ops/k8s/apps"
 	"github.com/portworx/sched-ops/task"
@@ -44,8 +47,6 @@ var (
 	PxOperatorVer24_1_0, _ = version.NewVersion("24.1.0-")
 )
 
-// TODO: Install portworx-operator in test automation
-
 // GetPXOperatorVersion returns the portworx operator version found
 func GetPXOperatorVersion(pxOperator *appsv1.Deployment) (*version.Version, error) {
 	imageTag, err := getPxOperatorImageTag(pxOperator)
@@ -210,3 +211,116 @@ func ValidatePxOperatorDeleted(namespace string) (*appsv1.Deployment, error) {
 	}
 	return pxOperatorDeployment, nil
 }
+
+// PxOperatorInstallOptions defines how PX Operator is installed in the test cluster
+type PxOperatorInstallOptions struct {
+	// UseOLM installs PX Operator via OLM from the operator registry catalog image, otherwise ManifestFiles are used
+	UseOLM bool
+	// OperatorTag is the PX Operator version to install via OLM
+	OperatorTag string
+	// ManifestFiles are the raw PX Operator manifests, e.g. deploy/operator.yaml, used when UseOLM is false
+	ManifestFiles []string
+	// OperatorImage overrides the PX Operator image in ManifestFiles, if not empty.
+	// It is unrelated to PxImageOverride, which is the Portworx image set on the StorageCluster.
+	OperatorImage string
+}
+
+// InstallAndValidatePxOperator installs PX Operator in PxNamespace and waits for it to be ready.
+// The returned objects are the ones created from manifests and should be passed to UninstallAndValidatePxOperator.
+// Objects are returned even if the install failed partway, so call UninstallAndValidatePxOperator when err != nil too.
+func InstallAndValidatePxOperator(opts *PxOperatorInstallOptions) ([]runtime.Object, error) {
+	if opts.UseOLM {
+		if err := DeployAndValidatePxOperatorViaMarketplace(opts.OperatorTag); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	return DeployAndValidatePxOperatorFromManifests(opts.OperatorImage, opts.ManifestFiles...)
+}
+
+// UninstallAndValidatePxOperator removes PX Operator installed by InstallAndValidatePxOperator and waits for it to be deleted
+func UninstallAndValidatePxOperator(opts *PxOperatorInstallOptions, objects []runtime.Object) error {
+	if opts.UseOLM {
+		return DeleteAndValidatePxOperatorViaMarketplace()
+	}
+	return DeleteAndValidatePxOperatorFromManifests(objects)
+}
+
+// DeployAndValidatePxOperatorFromManifests creates PX Operator objects from the given manifest files in PxNamespace,
+// overriding the operator image if operatorImage is not empty, and validates PX Operator deployment is ready.
+// If creation or validation fails, the objects are still returned so the caller can delete whatever was created.
+func DeployAndValidatePxOperatorFromManifests(operatorImage string, manifestFiles ...string) ([]runtime.Object, error) {
+	var objects []runtime.Object
+	for _, manifestFile := range manifestFiles {
+		objs, err := ParseSpecsWithFullPath(manifestFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse PX Operator manifest %s, Err: %v", manifestFile, err)
+		}
+		objects = append(objects, objs...)
+	}
+
+	for _, obj := range objects {
+		setPxOperatorObjectNamespace(obj, PxNamespace)
+		if dep, ok := obj.(*appsv1.Deployment); ok && dep.Name == pxOperatorDeploymentName && operatorImage != "" {
+			for i := range dep.Spec.Template.Spec.Containers {
+				if dep.Spec.Template.Spec.Containers[i].Name == PortworxOperatorContainerName {
+					dep.Spec.Template.Spec.Containers[i].Image = operatorImage
+				}
+			}
+		}
+	}
+
+	logrus.Infof("Deploy PX Operator from manifests %v in namespace [%s]", manifestFiles, PxNamespace)
+	if err := CreateObjects(objects); err != nil {
+		return objects, err
+	}
+
+	if _, err := ValidatePxOperator(PxNamespace); err != nil {
+		return objects, err
+	}
+	return objects, nil
+}
+
+// DeleteAndValidatePxOperatorFromManifests deletes PX Operator objects created by DeployAndValidatePxOperatorFromManifests
+// and validates PX Operator deployment is deleted
+func DeleteAndValidatePxOperatorFromManifests(objects []runtime.Object) error {
+	logrus.Infof("Delete PX Operator deployment in namespace [%s]", PxNamespace)
+	if err := DeleteObjects(objects); err != nil {
+		return err
+	}
+
+	if _, err := ValidatePxOperatorDeleted(PxNamespace); err != nil {
+		return err
+	}
+	return ValidateObjectsAreTerminated(objects, false)
+}
+
+// setPxOperatorObjectNamespace moves namespaced PX Operator objects to the given namespace,
+// including the service account subjects of cluster role bindings
+func setPxOperatorObjectNamespace(obj runtime.Object, namespace string) {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		o.Namespace = namespace
+	case *v1.ServiceAccount:
+		o.Namespace = namespace
+	case *v1.Service:
+		o.Namespace = namespace
+	case *v1.ConfigMap:
+		o.Namespace = namespace
+	case *rbacv1.Role:
+		o.Namespace = namespace
+	case *rbacv1.RoleBinding:
+		o.Namespace = namespace
+		setServiceAccountSubjectsNamespace(o.Subjects, namespace)
+	case *rbacv1.ClusterRoleBinding:
+		setServiceAccountSubjectsNamespace(o.Subjects, namespace)
+	}
+}
+
+func setServiceAccountSubjectsNamespace(subjects []rbacv1.Subject, namespace string) {
+	for i := range subjects {
+		if subjects[i].Kind == rbacv1.ServiceAccountKind {
+			subjects[i].Namespace = namespace
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-version"
+	"github.com/portworx/sched-ops/task"
+	"github.com/sirupsen/logrus"
+
+	testutil "github.com/libopenstorage/operator/pkg/util/test"
+)
+
+const (
+	// SpecGenChannelStable is the channel of released Portworx versions, e.g. https://install.portworx.com/2.13
+	SpecGenChannelStable = "stable"
+	// SpecGenChannelEdge is the channel of edge Portworx versions, e.g. https://edge-install.portworx.com/3.1
+	SpecGenChannelEdge = "edge"
+
+	defaultSpecGenTimeout       = 2 * time.Minute
+	defaultSpecGenRetryInterval = 10 * time.Second
+	defaultSpecGenCacheTTL      = 1 * time.Hour
+)
+
+// SpecGenError is returned when component images cannot be fetched from a spec gen URL
+type SpecGenError struct {
+	// URL is the version URL that was requested
+	URL string
+	// StatusCode is the HTTP status code of the last response, 0 if no response was received
+	StatusCode int
+	// Err is the underlying error
+	Err error
+}
+
+func (e *SpecGenError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("failed to get component images from %s, status code: %d, Err: %v", e.URL, e.StatusCode, e.Err)
+	}
+	return fmt.Sprintf("failed to get component images from %s, Err: %v", e.URL, e.Err)
+}
+
+func (e *SpecGenError) Unwrap() error {
+	return e.Err
+}
+
+// SpecGenURLInfo is the channel and version parsed from a spec gen URL
+type SpecGenURLInfo struct {
+	// Channel is either SpecGenChannelStable or SpecGenChannelEdge
+	Channel string
+	// Version is the Portworx version in the URL path, nil if the URL does not specify one
+	Version *version.Version
+}
+
+// ParseSpecGenURL parses the channel and Portworx version from the given spec gen URL
+func ParseSpecGenURL(specGenURL string) (*SpecGenURLInfo, error) {
+	u, err := url.Parse(specGenURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse URL [%s], Err: %v", specGenURL, err)
+	}
+
+	info := &SpecGenURLInfo{Channel: SpecGenChannelStable}
+	if strings.Contains(u.Host, "edge") {
+		info.Channel = SpecGenChannelEdge
+	}
+
+	// Not every spec gen URL carries a version, e.g. https://install.portworx.com
+	if v, err := version.NewVersion(path.Base(u.Path)); err == nil {
+		info.Version = v
+		// Pre-release versions, e.g. 3.1.0-rc1, are only published on the edge channel
+		if v.Prerelease() != "" {
+			info.Channel = SpecGenChannelEdge
+		}
+	}
+	return info, nil
+}
+
+// SpecGenClient gets component images from the Portworx spec generator, retrying on failures
+// and caching successful responses on disk. Edge responses are never cached, as edge builds
+// are republished under the same URL.
+type SpecGenClient struct {
+	// CacheDir is the directory where stable responses are cached, caching is disabled if empty
+	CacheDir string
+	// CacheTTL is how long a cached response is used before it is fetched again
+	CacheTTL time.Duration
+	// Timeout is the total time spent retrying a request
+	Timeout time.Duration
+	// RetryInterval is the time to wait between retries
+	RetryInterval time.Duration
+
+	httpClient *http.Client
+}
+
+// NewSpecGenClient returns a spec gen client with default retry settings caching responses in cacheDir
+func NewSpecGenClient(cacheDir string) *SpecGenClient {
+	return &SpecGenClient{
+		CacheDir:      cacheDir,
+		CacheTTL:      defaultSpecGenCacheTTL,
+		Timeout:       defaultSpecGenTimeout,
+		RetryInterval: defaultSpecGenRetryInterval,
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// GetImages gets component images for the given spec gen URL and k8s version
+func (c *SpecGenClient) GetImages(specGenURL, k8sVersion string) (map[string]string, error) {
+	pxVersionURL, err := testutil.ConstructVersionURL(specGenURL, k8sVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	useCache := c.useCache(specGenURL)
+	if useCache {
+		if data := c.readCache(pxVersionURL); data != nil {
+			logrus.Infof("Get component images from cached version URL %s", pxVersionURL)
+			return testutil.ParseImagesFromVersionManifest(data), nil
+		}
+	}
+
+	logrus.Infof("Get component images from version URL %s", pxVersionURL)
+	data, err := c.fetch(pxVersionURL)
+	if err != nil {
+		return nil, err
+	}
+	if useCache {
+		c.writeCache(pxVersionURL, data)
+	}
+
+	return testutil.ParseImagesFromVersionManifest(data), nil
+}
+
+// useCache returns true if caching is enabled and the spec gen URL is on the stable channel
+func (c *SpecGenClient) useCache(specGenURL string) bool {
+	if c.CacheDir == "" {
+		return false
+	}
+	info, err := ParseSpecGenURL(specGenURL)
+	return err == nil && info.Channel == SpecGenChannelStable
+}
+
+func (c *SpecGenClient) fetch(pxVersionURL string) ([]byte, error) {
+	var lastErr *SpecGenError
+	t := func() (interface{}, bool, error) {
+		resp, err := c.httpClient.Get(pxVersionURL)
+		if err != nil {
+			lastErr = &SpecGenError{URL: pxVersionURL, Err: err}
+			return nil, true, lastErr
+		}
+		defer resp.Body.Close()
+
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			lastErr = &SpecGenError{URL: pxVersionURL, StatusCode: resp.StatusCode, Err: err}
+			return nil, true, lastErr
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			lastErr = &SpecGenError{URL: pxVersionURL, StatusCode: resp.StatusCode, Err: fmt.Errorf("%s", strings.TrimSpace(string(data)))}
+			// Client errors will not go away with a retry
+			retry := resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+			return nil, retry, lastErr
+		}
+		return data, false, nil
+	}
+
+	out, err := task.DoRetryWithTimeout(t, c.Timeout, c.RetryInterval)
+	if err != nil {
+		if lastErr != nil {
+			return nil, lastErr
+		}
+		return nil, &SpecGenError{URL: pxVersionURL, Err: err}
+	}
+	return out.([]byte), nil
+}
+
+func (c *SpecGenClient) cachePath(pxVersionURL string) string {
+	sum := sha256.Sum256([]byte(pxVersionURL))
+	return filepath.Join(c.CacheDir, hex.EncodeToString(sum[:]))
+}
+
+func (c *SpecGenClient) readCache(pxVersionURL string) []byte {
+	cacheFile := c.cachePath(pxVersionURL)
+	info, err := os.Stat(cacheFile)
+	if err != nil || time.Since(info.ModTime()) > c.CacheTTL {
+		return nil
+	}
+	data, err := os.ReadFile(cacheFile)
+	if err != nil {
+		logrus.Warnf("Failed to read cached response for %s, Err: %v", pxVersionURL, err)
+		return nil
+	}
+	return data
+}
+
+// writeCache writes to a temporary file first and renames it into place,
+// so parallel tests never read a partially written response
+func (c *SpecGenClient) writeCache(pxVersionURL string, data []byte) {
+	if err := os.MkdirAll(c.CacheDir, 0755); err != nil {
+		logrus.Warnf("Failed to create spec gen cache dir %s, Err: %v", c.CacheDir, err)
+		return
+	}
+
+	tmpFile, err := os.CreateTemp(c.CacheDir, "tmp-")
+	if err != nil {
+		logrus.Warnf("Failed to cache response for %s, Err: %v", pxVersionURL, err)
+		return
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = tmpFile.Write(data)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), c.cachePath(pxVersionURL))
+	}
+	if err != nil {
+		logrus.Warnf("Failed to cache response for %s, Err: %v", pxVersionURL, err)
+	}
+}
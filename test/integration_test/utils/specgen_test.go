@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSpecGenURL(t *testing.T) {
+	tests := []struct {
+		name            string
+		specGenURL      string
+		expectedChannel string
+		expectedVersion string
+	}{
+		{
+			name:            "stable URL without version",
+			specGenURL:      "https://install.portworx.com",
+			expectedChannel: SpecGenChannelStable,
+		},
+		{
+			name:            "stable URL with trailing slash",
+			specGenURL:      "https://install.portworx.com/",
+			expectedChannel: SpecGenChannelStable,
+		},
+		{
+			name:            "stable URL with version",
+			specGenURL:      "https://install.portworx.com/2.13",
+			expectedChannel: SpecGenChannelStable,
+			expectedVersion: "2.13",
+		},
+		{
+			name:            "edge host with version",
+			specGenURL:      "https://edge-install.portworx.com/3.1",
+			expectedChannel: SpecGenChannelEdge,
+			expectedVersion: "3.1",
+		},
+		{
+			name:            "edge host without version",
+			specGenURL:      "https://edge-install.portworx.com",
+			expectedChannel: SpecGenChannelEdge,
+		},
+		{
+			name:            "pre-release version on stable host",
+			specGenURL:      "https://install.portworx.com/3.1.0-rc1",
+			expectedChannel: SpecGenChannelEdge,
+			expectedVersion: "3.1.0-rc1",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			info, err := ParseSpecGenURL(tc.specGenURL)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedChannel, info.Channel)
+			if tc.expectedVersion == "" {
+				require.Nil(t, info.Version)
+			} else {
+				require.NotNil(t, info.Version)
+				require.Equal(t, tc.expectedVersion, info.Version.Original())
+			}
+		})
+	}
+
+	// TestCase: Invalid URL
+	_, err := ParseSpecGenURL("://install.portworx.com")
+	require.Error(t, err)
+}
@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	corev1 "github.com/libopenstorage/operator/pkg/apis/core/v1"
+	testutil "github.com/libopenstorage/operator/pkg/util/test"
+)
+
+// SpecNode describes a node of the live cluster for spec templates
+type SpecNode struct {
+	// Name is the k8s node name
+	Name string
+	// Zone is the value of the topology.kubernetes.io/zone label, empty if not set
+	Zone string
+	// IP is the internal IP of the node
+	IP string
+}
+
+// SpecTemplateData is the live cluster inventory that test spec templates are rendered with
+type SpecTemplateData struct {
+	// Nodes are the nodes eligible to run Portworx sorted by name, so the same index selects the same node
+	// between tests, and the same node as NodeReplacePrefix in PopulateStorageCluster
+	Nodes []SpecNode
+	// Zones are the distinct zones of Nodes, sorted
+	Zones []string
+	// Namespace is the namespace where Portworx is deployed
+	Namespace string
+	// DeviceSpecs are the device paths passed in PxDeviceSpecs
+	DeviceSpecs []string
+	// KvdbDeviceSpec is the KVDB device passed in PxKvdbSpec
+	KvdbDeviceSpec string
+}
+
+// GetSpecTemplateData builds the template data from the live cluster and test parameters. Nodes are the ones
+// Portworx is expected to run on with the given placement, or with the default Portworx node affinity if it is nil,
+// and also on master nodes if runOnMaster is true, like the portworx.io/run-on-master annotation.
+func GetSpecTemplateData(placement *corev1.PlacementSpec, runOnMaster bool) (*SpecTemplateData, error) {
+	cluster := &corev1.StorageCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{"portworx.io/run-on-master": strconv.FormatBool(runOnMaster)},
+		},
+		Spec: corev1.StorageClusterSpec{
+			Placement: placement,
+		},
+	}
+	nodes, err := testutil.GetExpectedPxNodeList(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &SpecTemplateData{
+		Namespace:      PxNamespace,
+		KvdbDeviceSpec: PxKvdbSpec,
+	}
+	if PxDeviceSpecs != "" {
+		data.DeviceSpecs = strings.Split(PxDeviceSpecs, ";")
+	}
+
+	zones := make(map[string]bool)
+	for _, node := range nodes {
+		specNode := SpecNode{
+			Name: node.Name,
+			Zone: node.Labels[v1.LabelTopologyZone],
+		}
+		for _, addr := range node.Status.Addresses {
+			if addr.Type == v1.NodeInternalIP {
+				specNode.IP = addr.Address
+				break
+			}
+		}
+		if specNode.Zone != "" && !zones[specNode.Zone] {
+			zones[specNode.Zone] = true
+			data.Zones = append(data.Zones, specNode.Zone)
+		}
+		data.Nodes = append(data.Nodes, specNode)
+	}
+
+	sort.Slice(data.Nodes, func(i, j int) bool { return data.Nodes[i].Name < data.Nodes[j].Name })
+	sort.Strings(data.Zones)
+	return data, nil
+}
+
+// RenderSpecTemplate renders the given spec content as a Go template. Besides the fields of SpecTemplateData,
+// templates can use "node", "zone" and "device" functions that return the Nth item and fail if there are not enough, e.g.
+//
+//	nodeName: {{ node 0 }}
+//	- {{ device 1 }}
+//
+// A StorageCluster template cannot provide its own node inventory, so build the data with GetSpecTemplateData
+// using the placement the template sets, nil if it has none, before rendering it.
+func RenderSpecTemplate(name string, content []byte, data *SpecTemplateData) ([]byte, error) {
+	funcs := template.FuncMap{
+		"node": func(i int) (string, error) {
+			if i < 0 || i >= len(data.Nodes) {
+				return "", fmt.Errorf("requested node index %d but there are %d eligible Portworx nodes", i, len(data.Nodes))
+			}
+			return data.Nodes[i].Name, nil
+		},
+		"zone": func(i int) (string, error) {
+			if i < 0 || i >= len(data.Zones) {
+				return "", fmt.Errorf("requested zone index %d but there are %d zones", i, len(data.Zones))
+			}
+			return data.Zones[i], nil
+		},
+		"device": func(i int) (string, error) {
+			if i < 0 || i >= len(data.DeviceSpecs) {
+				return "", fmt.Errorf("requested device index %d but there are %d device specs", i, len(data.DeviceSpecs))
+			}
+			return data.DeviceSpecs[i], nil
+		},
+	}
+
+	tmpl, err := template.New(name).Funcs(funcs).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse spec template %s, Err: %v", name, err)
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return nil, fmt.Errorf("failed to render spec template %s, Err: %v", name, err)
+	}
+	return out.Bytes(), nil
+}
+
+// ParseSpecTemplate renders the file under testspec folder with the given data and returns all the valid k8s objects
+func ParseSpecTemplate(filename string, data *SpecTemplateData) ([]runtime.Object, error) {
+	fullPath := path.Join("testspec", filename)
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered, err := RenderSpecTemplate(filename, content, data)
+	if err != nil {
+		return nil, err
+	}
+	return parseSpecsFromReader(bytes.NewReader(rendered), fullPath)
+}
@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	corev1 "github.com/libopenstorage/operator/pkg/apis/core/v1"
+)
+
+func TestRenderSpecTemplate(t *testing.T) {
+	data := &SpecTemplateData{
+		Nodes: []SpecNode{
+			{Name: "node-a", Zone: "zone-1", IP: "10.0.0.1"},
+			{Name: "node-b", Zone: "zone-2", IP: "10.0.0.2"},
+		},
+		Zones:          []string{"zone-1", "zone-2"},
+		Namespace:      "portworx",
+		DeviceSpecs:    []string{"/dev/sdb", "/dev/sdc"},
+		KvdbDeviceSpec: "/dev/sdd",
+	}
+
+	tests := []struct {
+		name          string
+		content       string
+		expected      string
+		expectedError string
+	}{
+		{
+			name:     "node by index",
+			content:  "{{ node 0 }},{{ node 1 }}",
+			expected: "node-a,node-b",
+		},
+		{
+			name:     "zone by index",
+			content:  "{{ zone 1 }}",
+			expected: "zone-2",
+		},
+		{
+			name:     "device by index",
+			content:  "{{ device 0 }}",
+			expected: "/dev/sdb",
+		},
+		{
+			name:     "data fields",
+			content:  "{{ .Namespace }} {{ .KvdbDeviceSpec }} {{ (index .Nodes 1).IP }}",
+			expected: "portworx /dev/sdd 10.0.0.2",
+		},
+		{
+			name:     "range over nodes",
+			content:  "{{ range .Nodes }}{{ .Name }}={{ .Zone }};{{ end }}",
+			expected: "node-a=zone-1;node-b=zone-2;",
+		},
+		{
+			name:          "node index out of range",
+			content:       "{{ node 2 }}",
+			expectedError: "requested node index 2 but there are 2 eligible Portworx nodes",
+		},
+		{
+			name:          "negative node index",
+			content:       "{{ node -1 }}",
+			expectedError: "requested node index -1 but there are 2 eligible Portworx nodes",
+		},
+		{
+			name:          "zone index out of range",
+			content:       "{{ zone 2 }}",
+			expectedError: "requested zone index 2 but there are 2 zones",
+		},
+		{
+			name:          "negative zone index",
+			content:       "{{ zone -1 }}",
+			expectedError: "requested zone index -1 but there are 2 zones",
+		},
+		{
+			name:          "device index out of range",
+			content:       "{{ device 2 }}",
+			expectedError: "requested device index 2 but there are 2 device specs",
+		},
+		{
+			name:          "negative device index",
+			content:       "{{ device -1 }}",
+			expectedError: "requested device index -1 but there are 2 device specs",
+		},
+		{
+			name:          "unknown field",
+			content:       "{{ .Unknown }}",
+			expectedError: "failed to render spec template test.yaml",
+		},
+		{
+			name:          "invalid template",
+			content:       "{{ node 0 ",
+			expectedError: "failed to parse spec template test.yaml",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := RenderSpecTemplate("test.yaml", []byte(tc.content), data)
+			if tc.expectedError != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, string(out))
+		})
+	}
+}
+
+func TestRenderStorageClusterTemplate(t *testing.T) {
+	content := `apiVersion: core.libopenstorage.org/v1
+kind: StorageCluster
+metadata:
+  name: px-cluster
+  namespace: {{ .Namespace }}
+spec:
+  nodes:
+{{- range $i, $node := .Nodes }}
+  - selector:
+      nodeName: {{ node $i }}
+    storage:
+      devices:
+      - {{ device $i }}
+{{- end }}
+`
+	// The same template works with clusters of different sizes
+	for _, size := range []int{1, 2, 3} {
+		data := &SpecTemplateData{Namespace: "portworx"}
+		for i := 0; i < size; i++ {
+			data.Nodes = append(data.Nodes, SpecNode{Name: "node-" + string(rune('a'+i))})
+			data.DeviceSpecs = append(data.DeviceSpecs, "/dev/sd"+string(rune('b'+i)))
+		}
+
+		rendered, err := RenderSpecTemplate("storagecluster.yaml", []byte(content), data)
+		require.NoError(t, err)
+		objects, err := parseSpecsFromReader(bytes.NewReader(rendered), "storagecluster.yaml")
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+
+		cluster, ok := objects[0].(*corev1.StorageCluster)
+		require.True(t, ok)
+		require.Equal(t, "portworx", cluster.Namespace)
+		require.Len(t, cluster.Spec.Nodes, size)
+		for i, node := range cluster.Spec.Nodes {
+			require.Equal(t, data.Nodes[i].Name, node.Selector.NodeName)
+			require.Equal(t, []string{data.DeviceSpecs[i]}, *node.Storage.Devices)
+		}
+	}
+}
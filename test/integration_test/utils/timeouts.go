@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+type timeoutOverride struct {
+	flagName string
+	envName  string
+	value    *time.Duration
+	usage    string
+}
+
+var timeoutOverrides = []timeoutOverride{
+	{"validate-deploy-timeout", "PX_TEST_VALIDATE_DEPLOY_TIMEOUT", &DefaultValidateDeployTimeout, "Timeout for deployment validation"},
+	{"validate-deploy-retry-interval", "PX_TEST_VALIDATE_DEPLOY_RETRY_INTERVAL", &DefaultValidateDeployRetryInterval, "Retry interval for deployment validation"},
+	{"validate-upgrade-timeout", "PX_TEST_VALIDATE_UPGRADE_TIMEOUT", &DefaultValidateUpgradeTimeout, "Timeout for upgrade validation"},
+	{"validate-upgrade-retry-interval", "PX_TEST_VALIDATE_UPGRADE_RETRY_INTERVAL", &DefaultValidateUpgradeRetryInterval, "Retry interval for upgrade validation"},
+	{"validate-update-timeout", "PX_TEST_VALIDATE_UPDATE_TIMEOUT", &DefaultValidateUpdateTimeout, "Timeout for update validation"},
+	{"validate-update-retry-interval", "PX_TEST_VALIDATE_UPDATE_RETRY_INTERVAL", &DefaultValidateUpdateRetryInterval, "Retry interval for update validation"},
+	{"validate-uninstall-timeout", "PX_TEST_VALIDATE_UNINSTALL_TIMEOUT", &DefaultValidateUninstallTimeout, "Timeout for uninstall validation"},
+	{"validate-uninstall-retry-interval", "PX_TEST_VALIDATE_UNINSTALL_RETRY_INTERVAL", &DefaultValidateUninstallRetryInterval, "Retry interval for uninstall validation"},
+	{"validate-component-timeout", "PX_TEST_VALIDATE_COMPONENT_TIMEOUT", &DefaultValidateComponentTimeout, "Timeout for component validation"},
+	{"validate-component-retry-interval", "PX_TEST_VALIDATE_COMPONENT_RETRY_INTERVAL", &DefaultValidateComponentRetryInterval, "Retry interval for component validation"},
+	{"validate-application-timeout", "PX_TEST_VALIDATE_APPLICATION_TIMEOUT", &DefaultValidateApplicationTimeout, "Timeout for application validation"},
+	{"validate-application-retry-interval", "PX_TEST_VALIDATE_APPLICATION_RETRY_INTERVAL", &DefaultValidateApplicationRetryInterval, "Retry interval for application validation"},
+}
+
+// RegisterTimeoutFlags registers flags for all validation timeouts and retry intervals on the given FlagSet.
+// The default of each flag is taken from its environment variable when set (e.g. PX_TEST_VALIDATE_DEPLOY_TIMEOUT=30m),
+// otherwise the built-in value is kept. Flags passed on the command line take precedence over environment variables.
+func RegisterTimeoutFlags(fs *flag.FlagSet) error {
+	for _, o := range timeoutOverrides {
+		if val, ok := os.LookupEnv(o.envName); ok {
+			d, err := time.ParseDuration(val)
+			if err != nil {
+				return fmt.Errorf("failed to parse env var %s=%s as duration, Err: %v", o.envName, val, err)
+			}
+			*o.value = d
+		}
+		fs.DurationVar(o.value, o.flagName, *o.value, fmt.Sprintf("%s (env %s)", o.usage, o.envName))
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-version"
+	"github.com/portworx/sched-ops/k8s/operator"
+	"github.com/sirupsen/logrus"
+
+	"github.com/libopenstorage/operator/drivers/storage/portworx"
+	corev1 "github.com/libopenstorage/operator/pkg/apis/core/v1"
+	testutil "github.com/libopenstorage/operator/pkg/util/test"
+)
+
+// UpgradeHopReport is the result of a single hop of an UpgradePlan
+type UpgradeHopReport struct {
+	// FromURL is the spec gen URL the cluster was running before the hop, empty for the initial deployment
+	FromURL string
+	// ToURL is the spec gen URL of the hop
+	ToURL string
+	// PxVersion is the Portworx version parsed from ToURL
+	PxVersion *version.Version
+	// Duration is the time spent on the hop, including checks and validation
+	Duration time.Duration
+	// Err is the error that failed the hop, nil if the hop succeeded
+	Err error
+}
+
+// UpgradePlan deploys a StorageCluster with the first spec gen URL and upgrades it through the rest, one hop at a time
+type UpgradePlan struct {
+	// HopURLs are the spec gen URLs to go through, in order
+	HopURLs []string
+	// SpecGenClient gets the component images of each hop
+	SpecGenClient *SpecGenClient
+	// PreUpgradeCheck runs against the live cluster before each upgrade hop, the hop fails if it returns an error
+	PreUpgradeCheck func(cluster *corev1.StorageCluster) error
+	// PostUpgradeCheck runs after each hop, including the initial deployment, was validated
+	PostUpgradeCheck func(cluster *corev1.StorageCluster, specImages map[string]string) error
+}
+
+// NewUpgradePlan returns an upgrade plan through the given spec gen URLs, which checks the cluster is online before each upgrade.
+// It uses PxSpecGenClient to get component images.
+func NewUpgradePlan(hopURLs []string) *UpgradePlan {
+	return &UpgradePlan{
+		HopURLs:         hopURLs,
+		SpecGenClient:   PxSpecGenClient,
+		PreUpgradeCheck: validateStorageClusterIsOnline,
+	}
+}
+
+// Run executes the upgrade plan and stops at the first failed hop. It returns the latest live StorageCluster and a report per attempted hop.
+func (p *UpgradePlan) Run(cluster *corev1.StorageCluster) (*corev1.StorageCluster, []*UpgradeHopReport, error) {
+	if len(p.HopURLs) == 0 {
+		return nil, nil, fmt.Errorf("upgrade plan has no hops")
+	}
+	if p.SpecGenClient == nil {
+		return nil, nil, fmt.Errorf("upgrade plan has no spec gen client")
+	}
+
+	var reports []*UpgradeHopReport
+	var lastHopURL string
+	for _, hopURL := range p.HopURLs {
+		report := &UpgradeHopReport{
+			FromURL:   lastHopURL,
+			ToURL:     hopURL,
+			PxVersion: GetPxVersionFromSpecGenURL(hopURL),
+		}
+		reports = append(reports, report)
+
+		start := time.Now()
+		liveCluster, err := p.runHop(cluster, lastHopURL, hopURL)
+		report.Duration = time.Since(start)
+		if err != nil {
+			report.Err = err
+			return cluster, reports, fmt.Errorf("upgrade hop from [%s] to [%s] failed, Err: %v", lastHopURL, hopURL, err)
+		}
+		logrus.Infof("Upgrade hop to %s completed in %s", hopURL, report.Duration)
+
+		cluster = liveCluster
+		lastHopURL = hopURL
+	}
+	return cluster, reports, nil
+}
+
+func (p *UpgradePlan) runHop(cluster *corev1.StorageCluster, fromURL, toURL string) (*corev1.StorageCluster, error) {
+	specImages, err := p.SpecGenClient.GetImages(toURL, K8sVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	var timeout, interval time.Duration
+	if fromURL == "" {
+		logrus.Infof("Deploying starting cluster using %s", toURL)
+		if err := ConstructStorageCluster(cluster, toURL, specImages); err != nil {
+			return nil, err
+		}
+		if cluster, err = DeployStorageCluster(cluster, specImages); err != nil {
+			return nil, err
+		}
+		timeout, interval = DefaultValidateDeployTimeout, DefaultValidateDeployRetryInterval
+	} else {
+		// Get live StorageCluster
+		if cluster, err = operator.Instance().GetStorageCluster(cluster.Name, cluster.Namespace); err != nil {
+			return nil, err
+		}
+
+		if p.PreUpgradeCheck != nil {
+			if err := p.PreUpgradeCheck(cluster); err != nil {
+				return nil, fmt.Errorf("pre-upgrade check failed, Err: %v", err)
+			}
+		}
+
+		logrus.Infof("Upgrading from %s to %s", fromURL, toURL)
+		if err := ConstructStorageCluster(cluster, toURL, specImages); err != nil {
+			return nil, err
+		}
+
+		// Set defaults
+		k8sVersion, _ := version.NewVersion(K8sVersion)
+		if err := portworx.SetPortworxDefaults(cluster, k8sVersion); err != nil {
+			return nil, err
+		}
+
+		// Update live StorageCluster
+		if cluster, err = UpdateStorageCluster(cluster); err != nil {
+			return nil, err
+		}
+		timeout, interval = DefaultValidateUpgradeTimeout, DefaultValidateUpgradeRetryInterval
+	}
+
+	logrus.Infof("Validate StorageCluster %s", cluster.Name)
+	if err := testutil.ValidateStorageCluster(specImages, cluster, timeout, interval, true, ""); err != nil {
+		return nil, err
+	}
+
+	liveCluster, err := operator.Instance().GetStorageCluster(cluster.Name, cluster.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.PostUpgradeCheck != nil {
+		if err := p.PostUpgradeCheck(liveCluster, specImages); err != nil {
+			return nil, fmt.Errorf("post-upgrade check failed, Err: %v", err)
+		}
+	}
+	return liveCluster, nil
+}
+
+func validateStorageClusterIsOnline(cluster *corev1.StorageCluster) error {
+	_, err := testutil.ValidateStorageClusterIsOnline(cluster, DefaultValidateDeployTimeout, DefaultValidateDeployRetryInterval)
+	return err
+}
@@ -51,9 +51,11 @@ func MakeDNS1123Compatible(name string) string {
 // GetPxVersionFromSpecGenURL gets the px version to install or upgrade,
 // e.g. return version 2.9 for https://edge-install.portworx.com/2.9
 func GetPxVersionFromSpecGenURL(url string) *version.Version {
-	splitURL := strings.Split(url, "/")
-	v, _ := version.NewVersion(splitURL[len(splitURL)-1])
-	return v
+	info, err := ParseSpecGenURL(url)
+	if err != nil {
+		return nil
+	}
+	return info.Version
 }
 
 func addDefaultEnvVars(origEnvVarList []v1.EnvVar, specGenURL string) ([]v1.EnvVar, error) {